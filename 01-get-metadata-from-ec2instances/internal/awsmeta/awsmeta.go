@@ -0,0 +1,203 @@
+// Package awsmeta provides a resilient wrapper around the EC2 instance
+// metadata service (IMDS). It centralizes the retry policy, IMDSv2 token
+// handling, and hop-limit error surfacing that every metadata consumer in
+// this module would otherwise have to reimplement.
+package awsmeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// ErrHopLimitExceeded is returned when an IMDS token or metadata request
+// times out without ever receiving a response, which in container/pod
+// environments typically means the request exceeded the PUT response hop
+// limit (default 1) before reaching the instance's IMDS endpoint: the
+// packet's TTL expires in transit, so the client sees a transport timeout
+// rather than any HTTP status.
+var ErrHopLimitExceeded = errors.New("awsmeta: IMDS request exceeded hop limit")
+
+// ErrAccessDenied is returned when IMDS responds with 403 Forbidden, which
+// normally means IMDSv1 is disabled on the instance and the request didn't
+// carry a valid session token (e.g. token retrieval itself was rejected).
+var ErrAccessDenied = errors.New("awsmeta: IMDS denied the request")
+
+// ErrIMDSNotFound is returned when IMDS responds 404 Not Found to a
+// metadata path, e.g. a public IP query on an instance with none assigned.
+// Callers should treat this as "absent", not as a failure to reach IMDS.
+var ErrIMDSNotFound = errors.New("awsmeta: IMDS metadata path not found")
+
+const (
+	// DefaultMaxAttempts matches the retry budget amazon-eks-ami uses for
+	// its IMDS client: enough to ride out a few transient 5xx/timeout
+	// responses during early boot without stalling startup for long.
+	DefaultMaxAttempts = 5
+
+	// DefaultMaxBackoff caps the exponential backoff-with-jitter delay
+	// between retry attempts.
+	DefaultMaxBackoff = 5 * time.Second
+)
+
+// Options configures the retry behavior of a Client.
+type Options struct {
+	// MaxAttempts is the total number of attempts (including the first)
+	// made for a single metadata request.
+	MaxAttempts int
+
+	// MaxBackoff bounds the exponential-jitter backoff delay between
+	// retries.
+	MaxBackoff time.Duration
+
+	// Endpoint overrides the IMDS endpoint, e.g. to point at a mockimds
+	// server during tests or offline development. Empty means use the
+	// real IMDS endpoint.
+	Endpoint string
+}
+
+// WithEndpoint overrides the IMDS endpoint the Client talks to.
+func WithEndpoint(endpoint string) func(*Options) {
+	return func(o *Options) {
+		o.Endpoint = endpoint
+	}
+}
+
+func defaultOptions() Options {
+	return Options{
+		MaxAttempts: DefaultMaxAttempts,
+		MaxBackoff:  DefaultMaxBackoff,
+	}
+}
+
+// Client is a thin, retry-aware wrapper around *imds.Client. It always
+// requires IMDSv2 (token-backed) requests, disabling the SDK's IMDSv1
+// fallback so a token failure surfaces as an error instead of silently
+// dropping to insecure unauthenticated requests; the underlying SDK client
+// already fetches and transparently refreshes the token on expiry or a
+// 401, so Client only needs to add the retry policy and error translation
+// on top.
+type Client struct {
+	imds *imds.Client
+}
+
+// NewFromConfig builds a Client from an aws.Config, applying the fixed retry
+// policy described by Options (or its defaults) to the underlying IMDS
+// client.
+func NewFromConfig(cfg aws.Config, optFns ...func(*Options)) *Client {
+	opts := defaultOptions()
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
+	retryer := retry.NewStandard(func(ro *retry.StandardOptions) {
+		ro.MaxAttempts = opts.MaxAttempts
+		ro.Backoff = retry.NewExponentialJitterBackoff(opts.MaxBackoff)
+	})
+
+	client := imds.NewFromConfig(cfg, func(o *imds.Options) {
+		o.Retryer = retryer
+		o.EnableFallback = aws.FalseTernary
+		if opts.Endpoint != "" {
+			o.Endpoint = opts.Endpoint
+		}
+	})
+
+	return &Client{imds: client}
+}
+
+// GetMetadata fetches the raw string value at an arbitrary IMDS path,
+// e.g. "network/interfaces/macs/0e:.../local-ipv4s". Callers that need a
+// typed result should go through TypedIMDS instead.
+func (c *Client) GetMetadata(ctx context.Context, path string) (string, error) {
+	return c.getMetadataString(ctx, path)
+}
+
+// InstanceID returns the instance's instance-id.
+func (c *Client) InstanceID(ctx context.Context) (string, error) {
+	return c.getMetadataString(ctx, "instance-id")
+}
+
+// Region returns the region the instance is running in.
+func (c *Client) Region(ctx context.Context) (string, error) {
+	result, err := c.imds.GetRegion(ctx, &imds.GetRegionInput{})
+	if err != nil {
+		return "", translateError(err)
+	}
+	return result.Region, nil
+}
+
+// PrivateIP returns the instance's primary private IPv4 address.
+func (c *Client) PrivateIP(ctx context.Context) (string, error) {
+	return c.getMetadataString(ctx, "local-ipv4")
+}
+
+// InstanceType returns the instance's EC2 instance type.
+func (c *Client) InstanceType(ctx context.Context) (string, error) {
+	return c.getMetadataString(ctx, "instance-type")
+}
+
+// InstanceIdentityDocument returns the signed instance identity document.
+func (c *Client) InstanceIdentityDocument(ctx context.Context) (*imds.InstanceIdentityDocument, error) {
+	result, err := c.imds.GetInstanceIdentityDocument(ctx, &imds.GetInstanceIdentityDocumentInput{})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &result.InstanceIdentityDocument, nil
+}
+
+// IAMInfo returns the instance's IAM instance profile information.
+func (c *Client) IAMInfo(ctx context.Context) (*imds.IAMInfo, error) {
+	result, err := c.imds.GetIAMInfo(ctx, &imds.GetIAMInfoInput{})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &result.IAMInfo, nil
+}
+
+// getMetadataString fetches a single metadata path and returns its body as
+// a string.
+func (c *Client) getMetadataString(ctx context.Context, path string) (string, error) {
+	result, err := c.imds.GetMetadata(ctx, &imds.GetMetadataInput{Path: path})
+	if err != nil {
+		return "", translateError(err)
+	}
+	defer result.Content.Close()
+
+	content, err := io.ReadAll(result.Content)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// translateError recognizes the failure modes IMDS callers care about and
+// wraps them in sentinel errors: a 403 Forbidden response (IMDSv1 disabled,
+// no valid token) becomes ErrAccessDenied, a 404 becomes ErrIMDSNotFound,
+// and a transport-level timeout (the PUT response never arrives because its
+// TTL expired in transit) becomes ErrHopLimitExceeded.
+func translateError(err error) error {
+	var re *smithyhttp.ResponseError
+	if errors.As(err, &re) {
+		switch re.HTTPStatusCode() {
+		case http.StatusForbidden:
+			return fmt.Errorf("%w: %v", ErrAccessDenied, err)
+		case http.StatusNotFound:
+			return fmt.Errorf("%w: %v", ErrIMDSNotFound, err)
+		}
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return fmt.Errorf("%w: %v", ErrHopLimitExceeded, err)
+	}
+	return err
+}
@@ -0,0 +1,291 @@
+package awsmeta
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Key identifies one of the metadata values a MetadataCache tracks.
+type Key string
+
+// Well-known keys a MetadataCache can be asked to track.
+const (
+	KeyInstanceID     Key = "instance-id"
+	KeyRegion         Key = "region"
+	KeyIAMRole        Key = "iam-role"
+	KeyENIs           Key = "enis"
+	KeySecurityGroups Key = "security-groups"
+
+	// KeyInstanceTags tracks the instance's tags via IMDS's tags/instance
+	// path. That path requires the instance metadata tags opt-in
+	// (`aws ec2 modify-instance-metadata-options --instance-metadata-tags
+	// enabled`); on an instance without it enabled, refreshes fail with
+	// ErrIMDSNotFound and the cache keeps logging a refresh warning until
+	// it's turned on.
+	KeyInstanceTags Key = "instance-tags"
+)
+
+// DefaultRefreshInterval is how often a MetadataCache polls IMDS for keys
+// without a more specific per-key TTL.
+const DefaultRefreshInterval = 60 * time.Second
+
+// Change is delivered on a Subscribe channel when a tracked key's value
+// changes.
+type Change struct {
+	Key   Key
+	Value any
+}
+
+type cacheEntry struct {
+	value     any
+	fetchedAt time.Time
+}
+
+// MetadataCache polls IMDS in the background for a fixed set of keys and
+// serves the last-known-good value to callers without hitting IMDS on
+// every read. Each tracked key can be given its own refresh TTL via
+// WithKeyTTL, shorter or longer than the cache-wide refresh interval.
+// Only the Key constants declared above can be fetched; any other key
+// fails every refresh with an "unknown cache key" error.
+type MetadataCache struct {
+	client   *Client
+	interval time.Duration
+	ttls     map[Key]time.Duration
+	tick     time.Duration
+	logger   *slog.Logger
+
+	mu      sync.RWMutex
+	entries map[Key]cacheEntry
+	subs    map[Key][]chan Change
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// CacheOption configures a MetadataCache.
+type CacheOption func(*MetadataCache)
+
+// WithRefreshInterval overrides DefaultRefreshInterval.
+func WithRefreshInterval(interval time.Duration) CacheOption {
+	return func(c *MetadataCache) {
+		c.interval = interval
+	}
+}
+
+// WithKeyTTL sets a per-key refresh TTL, overriding the cache's default
+// refresh interval for that key only.
+func WithKeyTTL(key Key, ttl time.Duration) CacheOption {
+	return func(c *MetadataCache) {
+		c.ttls[key] = ttl
+	}
+}
+
+// WithLogger sets the logger used to report refresh failures. Defaults to
+// slog.Default().
+func WithLogger(logger *slog.Logger) CacheOption {
+	return func(c *MetadataCache) {
+		c.logger = logger
+	}
+}
+
+// NewMetadataCache creates a MetadataCache tracking keys. Call Start to
+// begin polling and Close to stop it.
+func NewMetadataCache(client *Client, keys []Key, opts ...CacheOption) *MetadataCache {
+	c := &MetadataCache{
+		client:   client,
+		interval: DefaultRefreshInterval,
+		ttls:     make(map[Key]time.Duration),
+		logger:   slog.Default(),
+		entries:  make(map[Key]cacheEntry, len(keys)),
+		subs:     make(map[Key][]chan Change),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, fn := range opts {
+		fn(c)
+	}
+
+	c.tick = c.interval
+	for _, key := range keys {
+		if _, ok := c.ttls[key]; !ok {
+			c.ttls[key] = c.interval
+		}
+		if c.ttls[key] < c.tick {
+			c.tick = c.ttls[key]
+		}
+		c.entries[key] = cacheEntry{}
+	}
+	return c
+}
+
+// Start performs an initial synchronous fetch of every tracked key and
+// then begins polling in the background.
+func (c *MetadataCache) Start(ctx context.Context) {
+	c.refreshDue(ctx, true)
+	go c.run()
+}
+
+// Close stops the background polling goroutine and closes every
+// subscriber channel.
+func (c *MetadataCache) Close() {
+	close(c.stop)
+	<-c.done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, chans := range c.subs {
+		for _, ch := range chans {
+			close(ch)
+		}
+	}
+}
+
+// Get returns the last-known-good value for key, if any has been fetched
+// yet.
+func (c *MetadataCache) Get(key Key) (any, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[key]
+	if !ok || entry.fetchedAt.IsZero() {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// Subscribe returns a channel that receives a Change whenever key's value
+// changes. The channel is buffered; a slow consumer drops updates rather
+// than blocking the refresh loop.
+func (c *MetadataCache) Subscribe(key Key) <-chan Change {
+	ch := make(chan Change, 16)
+
+	c.mu.Lock()
+	c.subs[key] = append(c.subs[key], ch)
+	c.mu.Unlock()
+
+	return ch
+}
+
+func (c *MetadataCache) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.refreshDue(context.Background(), false)
+		}
+	}
+}
+
+// refreshDue refetches every key whose TTL has elapsed, to within half a
+// tick (or every key, when force is true). fetchedAt is stamped after the
+// fetch returns, so without that slack a key whose TTL equals the tick
+// would miss every other tick.
+func (c *MetadataCache) refreshDue(ctx context.Context, force bool) {
+	now := time.Now()
+
+	c.mu.RLock()
+	due := make([]Key, 0, len(c.entries))
+	for key, entry := range c.entries {
+		if force || now.Sub(entry.fetchedAt)+c.tick/2 >= c.ttls[key] {
+			due = append(due, key)
+		}
+	}
+	c.mu.RUnlock()
+
+	for _, key := range due {
+		value, err := c.fetch(ctx, key)
+		if err != nil {
+			c.logger.Warn("awsmeta: metadata refresh failed, keeping last-known-good value",
+				"key", string(key), "error", err)
+			continue
+		}
+		c.store(key, value)
+	}
+}
+
+func (c *MetadataCache) fetch(ctx context.Context, key Key) (any, error) {
+	switch key {
+	case KeyInstanceID:
+		return c.client.InstanceID(ctx)
+	case KeyRegion:
+		return c.client.Region(ctx)
+	case KeyIAMRole:
+		info, err := c.client.IAMInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return info.InstanceProfileArn, nil
+	case KeyENIs:
+		return NewTypedIMDS(c.client).GetMACs(ctx)
+	case KeySecurityGroups:
+		typed := NewTypedIMDS(c.client)
+		mac, err := typed.GetMAC(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return typed.GetSecurityGroupIDs(ctx, mac)
+	case KeyInstanceTags:
+		return c.fetchInstanceTags(ctx)
+	default:
+		return nil, fmt.Errorf("awsmeta: unknown cache key %q", key)
+	}
+}
+
+// fetchInstanceTags reads the instance's tag keys from IMDS's
+// tags/instance listing (one key per line, which may itself contain
+// spaces), then fetches each key's value individually, as IMDS exposes no
+// single endpoint returning the full key/value map.
+func (c *MetadataCache) fetchInstanceTags(ctx context.Context) (map[string]string, error) {
+	raw, err := c.client.GetMetadata(ctx, "tags/instance")
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(raw, "\n")
+	tags := make(map[string]string, len(lines))
+	for _, line := range lines {
+		key := strings.TrimSpace(line)
+		if key == "" {
+			continue
+		}
+		value, err := c.client.GetMetadata(ctx, "tags/instance/"+url.PathEscape(key))
+		if err != nil {
+			return nil, err
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
+
+func (c *MetadataCache) store(key Key, value any) {
+	c.mu.Lock()
+	prev, hadPrev := c.entries[key]
+	changed := !hadPrev || !reflect.DeepEqual(prev.value, value)
+	c.entries[key] = cacheEntry{value: value, fetchedAt: time.Now()}
+	subs := append([]chan Change(nil), c.subs[key]...)
+	c.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	for _, ch := range subs {
+		select {
+		case ch <- Change{Key: key, Value: value}:
+		default:
+			c.logger.Warn("awsmeta: dropping metadata change notification, subscriber channel full",
+				"key", string(key))
+		}
+	}
+}
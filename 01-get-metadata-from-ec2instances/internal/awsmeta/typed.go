@@ -0,0 +1,186 @@
+package awsmeta
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// TypedIMDS decorates a Client with accessors that parse the raw IMDS
+// response body into the Go type the caller actually wants, instead of
+// leaving every consumer to re-parse space/newline separated lists, IPs,
+// and CIDRs by hand.
+type TypedIMDS struct {
+	client *Client
+}
+
+// NewTypedIMDS wraps an existing Client.
+func NewTypedIMDS(client *Client) *TypedIMDS {
+	return &TypedIMDS{client: client}
+}
+
+// GetAZ returns the availability zone the instance is running in.
+func (t *TypedIMDS) GetAZ(ctx context.Context) (string, error) {
+	return t.client.GetMetadata(ctx, "placement/availability-zone")
+}
+
+// GetInstanceID returns the instance's instance-id.
+func (t *TypedIMDS) GetInstanceID(ctx context.Context) (string, error) {
+	return t.client.InstanceID(ctx)
+}
+
+// GetInstanceType returns the instance's EC2 instance type.
+func (t *TypedIMDS) GetInstanceType(ctx context.Context) (string, error) {
+	return t.client.InstanceType(ctx)
+}
+
+// GetLocalIPv4 returns the instance's primary private IPv4 address.
+func (t *TypedIMDS) GetLocalIPv4(ctx context.Context) (net.IP, error) {
+	raw, err := t.client.PrivateIP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseIP(raw)
+}
+
+// GetPublicIPv4s returns the instance's public IPv4 addresses, if any.
+// Instances with no public IP return ErrIMDSNotFound.
+func (t *TypedIMDS) GetPublicIPv4s(ctx context.Context) ([]net.IP, error) {
+	raw, err := t.client.GetMetadata(ctx, "public-ipv4")
+	if err != nil {
+		return nil, err
+	}
+	return parseIPList(raw)
+}
+
+// GetMAC returns the MAC address of the instance's primary network
+// interface.
+func (t *TypedIMDS) GetMAC(ctx context.Context) (string, error) {
+	raw, err := t.client.GetMetadata(ctx, "mac")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(raw), nil
+}
+
+// GetMACs returns the MAC addresses of all of the instance's network
+// interfaces.
+func (t *TypedIMDS) GetMACs(ctx context.Context) ([]string, error) {
+	raw, err := t.client.GetMetadata(ctx, "network/interfaces/macs")
+	if err != nil {
+		return nil, err
+	}
+	return parseStringList(raw, "/"), nil
+}
+
+// GetInterfaceIPv4s returns the private IPv4 addresses associated with the
+// network interface identified by mac.
+func (t *TypedIMDS) GetInterfaceIPv4s(ctx context.Context, mac string) ([]net.IP, error) {
+	raw, err := t.client.GetMetadata(ctx, fmt.Sprintf("network/interfaces/macs/%s/local-ipv4s", mac))
+	if err != nil {
+		return nil, err
+	}
+	return parseIPList(raw)
+}
+
+// GetInterfaceIPv6s returns the IPv6 addresses associated with the network
+// interface identified by mac.
+func (t *TypedIMDS) GetInterfaceIPv6s(ctx context.Context, mac string) ([]net.IP, error) {
+	raw, err := t.client.GetMetadata(ctx, fmt.Sprintf("network/interfaces/macs/%s/ipv6s", mac))
+	if err != nil {
+		return nil, err
+	}
+	return parseIPList(raw)
+}
+
+// GetInterfaceSubnetCIDR returns the IPv4 CIDR block of the subnet the
+// network interface identified by mac is attached to.
+func (t *TypedIMDS) GetInterfaceSubnetCIDR(ctx context.Context, mac string) (*net.IPNet, error) {
+	raw, err := t.client.GetMetadata(ctx, fmt.Sprintf("network/interfaces/macs/%s/subnet-ipv4-cidr-block", mac))
+	if err != nil {
+		return nil, err
+	}
+	_, ipNet, err := net.ParseCIDR(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("awsmeta: parse subnet CIDR %q: %w", raw, err)
+	}
+	return ipNet, nil
+}
+
+// GetSecurityGroupIDs returns the IDs of the security groups attached to
+// the network interface identified by mac.
+func (t *TypedIMDS) GetSecurityGroupIDs(ctx context.Context, mac string) ([]string, error) {
+	raw, err := t.client.GetMetadata(ctx, fmt.Sprintf("network/interfaces/macs/%s/security-group-ids", mac))
+	if err != nil {
+		return nil, err
+	}
+	return parseStringList(raw, ""), nil
+}
+
+// GetVPCID returns the ID of the VPC the instance's primary network
+// interface belongs to.
+func (t *TypedIMDS) GetVPCID(ctx context.Context) (string, error) {
+	mac, err := t.GetMAC(ctx)
+	if err != nil {
+		return "", err
+	}
+	raw, err := t.client.GetMetadata(ctx, fmt.Sprintf("network/interfaces/macs/%s/vpc-id", mac))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(raw), nil
+}
+
+// GetSubnetID returns the ID of the subnet the instance's primary network
+// interface belongs to.
+func (t *TypedIMDS) GetSubnetID(ctx context.Context) (string, error) {
+	mac, err := t.GetMAC(ctx)
+	if err != nil {
+		return "", err
+	}
+	raw, err := t.client.GetMetadata(ctx, fmt.Sprintf("network/interfaces/macs/%s/subnet-id", mac))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(raw), nil
+}
+
+// parseIP parses a single IMDS address response.
+func parseIP(raw string) (net.IP, error) {
+	s := strings.TrimSpace(raw)
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("awsmeta: invalid IP address %q", s)
+	}
+	return ip, nil
+}
+
+// parseIPList parses IMDS's newline-separated address list responses.
+func parseIPList(raw string) ([]net.IP, error) {
+	fields := strings.Fields(raw)
+	ips := make([]net.IP, 0, len(fields))
+	for _, f := range fields {
+		ip, err := parseIP(f)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// parseStringList parses IMDS's newline-separated listing responses,
+// trimming a trailing suffix (e.g. "/" from the macs listing) off each
+// entry.
+func parseStringList(raw, trimSuffix string) []string {
+	fields := strings.Fields(raw)
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if trimSuffix != "" {
+			f = strings.TrimSuffix(f, trimSuffix)
+		}
+		out = append(out, f)
+	}
+	return out
+}
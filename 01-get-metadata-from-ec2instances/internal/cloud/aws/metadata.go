@@ -0,0 +1,168 @@
+// Package aws implements the cloud-provider metadata abstraction for AWS,
+// combining IMDS (for "what am I") with EC2 DescribeInstances (for
+// everything IMDS cannot answer on its own, such as tags and sibling
+// instances).
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/william-hou-ca/go-aws-sdk/01-get-metadata-from-ec2instances/internal/awsmeta"
+)
+
+// DeploymentUIDTagKey is the tag key shared by every instance belonging to
+// the same deployment. List uses it to find siblings of the current
+// instance.
+const DeploymentUIDTagKey = "deployment-uid"
+
+// InstanceMetadata is the cloud-agnostic shape higher-level callers consume.
+type InstanceMetadata struct {
+	Name       string
+	ProviderID string
+	Role       string
+	PrivateIPs []string
+	PublicIPs  []string
+	VPCID      string
+	SubnetID   string
+}
+
+// ProviderMetadata is implemented by Metadata. Defining it separately lets
+// callers depend on the interface rather than the concrete AWS type.
+type ProviderMetadata interface {
+	Self(ctx context.Context) (InstanceMetadata, error)
+	List(ctx context.Context) ([]InstanceMetadata, error)
+	GetInstanceTag(ctx context.Context, key string) (string, error)
+	LoadBalancerEndpoint(ctx context.Context) (string, error)
+}
+
+// Metadata answers metadata questions about the current instance and its
+// deployment by combining an IMDS client with an EC2 client. It implements
+// ProviderMetadata.
+type Metadata struct {
+	imds *awsmeta.Client
+	ec2  *ec2.Client
+}
+
+// New builds a Metadata from an aws.Config.
+func New(cfg awssdk.Config) *Metadata {
+	return &Metadata{
+		imds: awsmeta.NewFromConfig(cfg),
+		ec2:  ec2.NewFromConfig(cfg),
+	}
+}
+
+// Self returns the metadata of the instance the caller is running on.
+func (m *Metadata) Self(ctx context.Context) (InstanceMetadata, error) {
+	instanceID, err := m.imds.InstanceID(ctx)
+	if err != nil {
+		return InstanceMetadata{}, fmt.Errorf("getting own instance ID: %w", err)
+	}
+
+	instance, err := m.describeInstance(ctx, instanceID)
+	if err != nil {
+		return InstanceMetadata{}, err
+	}
+	return toInstanceMetadata(instance), nil
+}
+
+// List returns the metadata of every instance that shares this instance's
+// DeploymentUIDTagKey value.
+func (m *Metadata) List(ctx context.Context) ([]InstanceMetadata, error) {
+	uid, err := m.GetInstanceTag(ctx, DeploymentUIDTagKey)
+	if err != nil {
+		return nil, fmt.Errorf("getting own %s tag: %w", DeploymentUIDTagKey, err)
+	}
+
+	var instances []InstanceMetadata
+	paginator := ec2.NewDescribeInstancesPaginator(m.ec2, &ec2.DescribeInstancesInput{
+		Filters: []types.Filter{
+			{
+				Name:   awssdk.String(fmt.Sprintf("tag:%s", DeploymentUIDTagKey)),
+				Values: []string{uid},
+			},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing deployment instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				instances = append(instances, toInstanceMetadata(instance))
+			}
+		}
+	}
+	return instances, nil
+}
+
+// GetInstanceTag returns the value of the given tag on the current
+// instance, read via DescribeInstances rather than the IMDS tags API
+// (which requires opting in per-instance and can serve stale values).
+func (m *Metadata) GetInstanceTag(ctx context.Context, key string) (string, error) {
+	instanceID, err := m.imds.InstanceID(ctx)
+	if err != nil {
+		return "", fmt.Errorf("getting own instance ID: %w", err)
+	}
+
+	instance, err := m.describeInstance(ctx, instanceID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, tag := range instance.Tags {
+		if awssdk.ToString(tag.Key) == key {
+			return awssdk.ToString(tag.Value), nil
+		}
+	}
+	return "", fmt.Errorf("tag %q not found on instance %s", key, instanceID)
+}
+
+// LoadBalancerEndpoint returns the DNS name of the load balancer fronting
+// this deployment, read from the "load-balancer-dns" tag on the current
+// instance.
+func (m *Metadata) LoadBalancerEndpoint(ctx context.Context) (string, error) {
+	return m.GetInstanceTag(ctx, "load-balancer-dns")
+}
+
+func (m *Metadata) describeInstance(ctx context.Context, instanceID string) (types.Instance, error) {
+	out, err := m.ec2.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		return types.Instance{}, fmt.Errorf("describing instance %s: %w", instanceID, err)
+	}
+	if len(out.Reservations) == 0 || len(out.Reservations[0].Instances) == 0 {
+		return types.Instance{}, fmt.Errorf("instance %s not found", instanceID)
+	}
+	return out.Reservations[0].Instances[0], nil
+}
+
+func toInstanceMetadata(instance types.Instance) InstanceMetadata {
+	meta := InstanceMetadata{
+		ProviderID: awssdk.ToString(instance.InstanceId),
+		VPCID:      awssdk.ToString(instance.VpcId),
+		SubnetID:   awssdk.ToString(instance.SubnetId),
+	}
+
+	if instance.PrivateIpAddress != nil {
+		meta.PrivateIPs = append(meta.PrivateIPs, awssdk.ToString(instance.PrivateIpAddress))
+	}
+	if instance.PublicIpAddress != nil {
+		meta.PublicIPs = append(meta.PublicIPs, awssdk.ToString(instance.PublicIpAddress))
+	}
+	if instance.IamInstanceProfile != nil {
+		meta.Role = awssdk.ToString(instance.IamInstanceProfile.Arn)
+	}
+	for _, tag := range instance.Tags {
+		if awssdk.ToString(tag.Key) == "Name" {
+			meta.Name = awssdk.ToString(tag.Value)
+		}
+	}
+	return meta
+}
@@ -0,0 +1,125 @@
+package mockimds
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecurityCredentials mirrors the body IMDS returns from
+// /iam/security-credentials/<role>.
+type SecurityCredentials struct {
+	Code            string `json:"Code" yaml:"code"`
+	LastUpdated     string `json:"LastUpdated" yaml:"last_updated"`
+	Type            string `json:"Type" yaml:"type"`
+	AccessKeyID     string `json:"AccessKeyId" yaml:"access_key_id"`
+	SecretAccessKey string `json:"SecretAccessKey" yaml:"secret_access_key"`
+	Token           string `json:"Token" yaml:"token"`
+	Expiration      string `json:"Expiration" yaml:"expiration"`
+}
+
+// IAMInfo mirrors the body IMDS returns from /iam/info.
+type IAMInfo struct {
+	Code               string `json:"Code" yaml:"code"`
+	LastUpdated        string `json:"LastUpdated" yaml:"last_updated"`
+	InstanceProfileArn string `json:"InstanceProfileArn" yaml:"instance_profile_arn"`
+	InstanceProfileID  string `json:"InstanceProfileId" yaml:"instance_profile_id"`
+}
+
+// Fixtures is the full scripted metadata surface a Server serves. It is
+// loadable from a YAML or JSON file so tests can assert behavior against a
+// known, offline metadata tree.
+type Fixtures struct {
+	InstanceID               string                         `json:"instance_id" yaml:"instance_id"`
+	InstanceType             string                         `json:"instance_type" yaml:"instance_type"`
+	LocalIPv4                string                         `json:"local_ipv4" yaml:"local_ipv4"`
+	Region                   string                         `json:"region" yaml:"region"`
+	AvailabilityZone         string                         `json:"availability_zone" yaml:"availability_zone"`
+	IAMInfo                  IAMInfo                        `json:"iam_info" yaml:"iam_info"`
+	SecurityCredentials      map[string]SecurityCredentials `json:"security_credentials" yaml:"security_credentials"`
+	InstanceIdentityDocument json.RawMessage                `json:"instance_identity_document" yaml:"instance_identity_document"`
+
+	// TokenTTLSeconds is the TTL the server grants for tokens issued via
+	// PUT /latest/api/token when the request carries no TTL header; a
+	// requested TTL, longer or shorter, replaces it.
+	TokenTTLSeconds int64 `json:"token_ttl_seconds" yaml:"token_ttl_seconds"`
+}
+
+// fixturesYAML mirrors Fixtures field-for-field, except
+// InstanceIdentityDocument is decoded as a yaml.Node rather than
+// json.RawMessage: yaml.v3 can't unmarshal a YAML mapping directly into
+// []byte, so UnmarshalYAML decodes the node generically and re-encodes it
+// as JSON instead.
+type fixturesYAML struct {
+	InstanceID               string                         `yaml:"instance_id"`
+	InstanceType             string                         `yaml:"instance_type"`
+	LocalIPv4                string                         `yaml:"local_ipv4"`
+	Region                   string                         `yaml:"region"`
+	AvailabilityZone         string                         `yaml:"availability_zone"`
+	IAMInfo                  IAMInfo                        `yaml:"iam_info"`
+	SecurityCredentials      map[string]SecurityCredentials `yaml:"security_credentials"`
+	InstanceIdentityDocument yaml.Node                      `yaml:"instance_identity_document"`
+	TokenTTLSeconds          int64                          `yaml:"token_ttl_seconds"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler so InstanceIdentityDocument,
+// which is a JSON document embedded as a YAML mapping, round-trips to
+// json.RawMessage instead of failing with "cannot unmarshal !!map into
+// []uint8".
+func (f *Fixtures) UnmarshalYAML(value *yaml.Node) error {
+	var alias fixturesYAML
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+
+	f.InstanceID = alias.InstanceID
+	f.InstanceType = alias.InstanceType
+	f.LocalIPv4 = alias.LocalIPv4
+	f.Region = alias.Region
+	f.AvailabilityZone = alias.AvailabilityZone
+	f.IAMInfo = alias.IAMInfo
+	f.SecurityCredentials = alias.SecurityCredentials
+	f.TokenTTLSeconds = alias.TokenTTLSeconds
+
+	if !alias.InstanceIdentityDocument.IsZero() {
+		var doc any
+		if err := alias.InstanceIdentityDocument.Decode(&doc); err != nil {
+			return fmt.Errorf("mockimds: decoding instance_identity_document: %w", err)
+		}
+		encoded, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("mockimds: encoding instance_identity_document: %w", err)
+		}
+		f.InstanceIdentityDocument = encoded
+	}
+	return nil
+}
+
+// LoadFixturesFile loads Fixtures from a .json, .yaml, or .yml file.
+func LoadFixturesFile(path string) (Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Fixtures{}, fmt.Errorf("mockimds: reading fixtures file %s: %w", path, err)
+	}
+
+	var fixtures Fixtures
+	switch ext := filepath.Ext(path); ext {
+	case ".json":
+		err = json.Unmarshal(data, &fixtures)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &fixtures)
+	default:
+		return Fixtures{}, fmt.Errorf("mockimds: unsupported fixtures file extension %q", ext)
+	}
+	if err != nil {
+		return Fixtures{}, fmt.Errorf("mockimds: parsing fixtures file %s: %w", path, err)
+	}
+
+	if fixtures.TokenTTLSeconds == 0 {
+		fixtures.TokenTTLSeconds = DefaultTokenTTLSeconds
+	}
+	return fixtures, nil
+}
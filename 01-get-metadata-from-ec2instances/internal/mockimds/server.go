@@ -0,0 +1,243 @@
+// Package mockimds stands up a local HTTP server that serves a scripted
+// subset of the EC2 instance metadata service, so tests and offline
+// development do not depend on real EC2. It enforces IMDSv2 (token-only)
+// access the same way real IMDS does.
+package mockimds
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAddr is the address Server listens on when none is given: a
+// loopback address with an OS-assigned port, safe to use in parallel
+// tests.
+const DefaultAddr = "127.0.0.1:0"
+
+// RealIMDSAddr is the real IMDS link-local address. Binding it requires
+// CAP_NET_BIND_SERVICE (or root) since it is a privileged, non-loopback
+// address; pass it to NewServer explicitly when that's available.
+const RealIMDSAddr = "169.254.169.254:80"
+
+// DefaultTokenTTLSeconds is used when a fixtures file does not specify a
+// token TTL and a token request does not override it.
+const DefaultTokenTTLSeconds = 21600
+
+// tokenHeader and ttlHeader match the headers the real IMDSv2 endpoint
+// requires.
+const (
+	tokenHeader = "X-aws-ec2-metadata-token"
+	ttlHeader   = "X-aws-ec2-metadata-token-ttl-seconds"
+)
+
+// Server is a mock IMDS HTTP server backed by a fixed set of Fixtures.
+type Server struct {
+	fixtures Fixtures
+	listener net.Listener
+	http     *http.Server
+
+	mu     sync.Mutex
+	tokens map[string]time.Time
+}
+
+// NewServer creates a Server listening on addr (DefaultAddr if empty) and
+// serving the given fixtures. It does not start serving requests until
+// Start is called.
+func NewServer(addr string, fixtures Fixtures) (*Server, error) {
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	if fixtures.TokenTTLSeconds == 0 {
+		fixtures.TokenTTLSeconds = DefaultTokenTTLSeconds
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("mockimds: listening on %s: %w", addr, err)
+	}
+
+	s := &Server{
+		fixtures: fixtures,
+		listener: listener,
+		tokens:   make(map[string]time.Time),
+	}
+	s.http = &http.Server{Handler: s.routes()}
+	return s, nil
+}
+
+// Addr returns the address the server is actually listening on, useful
+// when NewServer was given a ":0" port.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Endpoint returns the base URL to pass as imds.Options.Endpoint.
+func (s *Server) Endpoint() string {
+	return "http://" + s.Addr()
+}
+
+// Start begins serving requests in the background.
+func (s *Server) Start() {
+	go s.http.Serve(s.listener)
+}
+
+// Close shuts down the server.
+func (s *Server) Close(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest/api/token", s.handleToken)
+	mux.HandleFunc("/latest/meta-data/instance-id", s.requireToken(s.handleText(func() string { return s.fixtures.InstanceID })))
+	mux.HandleFunc("/latest/meta-data/instance-type", s.requireToken(s.handleText(func() string { return s.fixtures.InstanceType })))
+	mux.HandleFunc("/latest/meta-data/local-ipv4", s.requireToken(s.handleText(func() string { return s.fixtures.LocalIPv4 })))
+	mux.HandleFunc("/latest/meta-data/placement/availability-zone", s.requireToken(s.handleText(func() string { return s.fixtures.AvailabilityZone })))
+	mux.HandleFunc("/latest/meta-data/iam/info", s.requireToken(s.handleJSON(func() any { return s.fixtures.IAMInfo })))
+	mux.HandleFunc("/latest/meta-data/iam/security-credentials/", s.requireToken(s.handleSecurityCredentials))
+	mux.HandleFunc("/latest/dynamic/instance-identity/document", s.requireToken(s.handleIdentityDocument))
+	return mux
+}
+
+// handleToken implements PUT /latest/api/token, issuing a random token
+// with the requested (or default) TTL.
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ttl := time.Duration(s.fixtures.TokenTTLSeconds) * time.Second
+	if raw := r.Header.Get(ttlHeader); raw != "" {
+		if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			ttl = time.Duration(seconds) * time.Second
+		}
+	}
+
+	token, err := newToken()
+	if err != nil {
+		http.Error(w, "failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	s.tokens[token] = time.Now().Add(ttl)
+	s.mu.Unlock()
+
+	w.Header().Set(ttlHeader, strconv.FormatInt(int64(ttl.Seconds()), 10))
+	w.Write([]byte(token))
+}
+
+// requireToken enforces that a valid, unexpired token accompanies the
+// request, returning 401 otherwise, exactly as real IMDSv2 does once
+// IMDSv1 fallback is disabled.
+func (s *Server) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(tokenHeader)
+		if token == "" || !s.validToken(token) {
+			http.Error(w, "missing or invalid metadata token", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) validToken(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiry, ok := s.tokens[token]
+	if !ok {
+		return false
+	}
+	if time.Now().After(expiry) {
+		delete(s.tokens, token)
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleText(value func() string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v := value()
+		if v == "" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(v))
+	}
+}
+
+func (s *Server) handleJSON(value func() any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, value())
+	}
+}
+
+func (s *Server) handleSecurityCredentials(w http.ResponseWriter, r *http.Request) {
+	role := strings.TrimPrefix(r.URL.Path, "/latest/meta-data/iam/security-credentials/")
+	if role == "" {
+		// Listing roles: IMDS returns one role name per line.
+		roles := make([]string, 0, len(s.fixtures.SecurityCredentials))
+		for name := range s.fixtures.SecurityCredentials {
+			roles = append(roles, name)
+		}
+		w.Write([]byte(strings.Join(roles, "\n")))
+		return
+	}
+
+	creds, ok := s.fixtures.SecurityCredentials[role]
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, creds)
+}
+
+// handleIdentityDocument serves /dynamic/instance-identity/document, which
+// is the path the AWS SDK's GetRegion actually reads (there is no
+// dedicated placement/region handler: the SDK never requests it). The
+// fixture's Region is overlaid onto the document's "region" field so
+// fixtures files can set Region alone without hand-authoring a full
+// identity document.
+func (s *Server) handleIdentityDocument(w http.ResponseWriter, r *http.Request) {
+	doc := map[string]any{}
+	if len(s.fixtures.InstanceIdentityDocument) > 0 {
+		if err := json.Unmarshal(s.fixtures.InstanceIdentityDocument, &doc); err != nil {
+			http.Error(w, "invalid fixture instance identity document", http.StatusInternalServerError)
+			return
+		}
+	}
+	if s.fixtures.Region != "" {
+		doc["region"] = s.fixtures.Region
+	}
+	if len(doc) == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, doc)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,81 @@
+package mockimds_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/william-hou-ca/go-aws-sdk/01-get-metadata-from-ec2instances/internal/awsmeta"
+	"github.com/william-hou-ca/go-aws-sdk/01-get-metadata-from-ec2instances/internal/mockimds"
+)
+
+func startServer(t *testing.T, fixtures mockimds.Fixtures) *mockimds.Server {
+	t.Helper()
+
+	server, err := mockimds.NewServer("", fixtures)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	server.Start()
+	t.Cleanup(func() { server.Close(context.Background()) })
+	return server
+}
+
+func TestServerServesScriptedMetadata(t *testing.T) {
+	fixtures := mockimds.Fixtures{
+		InstanceID:       "i-0123456789abcdef0",
+		InstanceType:     "t3.micro",
+		LocalIPv4:        "10.0.0.5",
+		Region:           "us-west-2",
+		AvailabilityZone: "us-west-2a",
+		IAMInfo: mockimds.IAMInfo{
+			Code:               "Success",
+			LastUpdated:        "2024-01-01T00:00:00Z",
+			InstanceProfileArn: "arn:aws:iam::123456789012:instance-profile/example",
+		},
+	}
+	server := startServer(t, fixtures)
+
+	client := awsmeta.NewFromConfig(aws.Config{}, awsmeta.WithEndpoint(server.Endpoint()))
+	ctx := context.Background()
+
+	if got, err := client.InstanceID(ctx); err != nil || got != fixtures.InstanceID {
+		t.Fatalf("InstanceID() = %q, %v; want %q, <nil>", got, err, fixtures.InstanceID)
+	}
+	if got, err := client.InstanceType(ctx); err != nil || got != fixtures.InstanceType {
+		t.Fatalf("InstanceType() = %q, %v; want %q, <nil>", got, err, fixtures.InstanceType)
+	}
+	if got, err := client.PrivateIP(ctx); err != nil || got != fixtures.LocalIPv4 {
+		t.Fatalf("PrivateIP() = %q, %v; want %q, <nil>", got, err, fixtures.LocalIPv4)
+	}
+	// Region is read via GetRegion, which the SDK serves from the
+	// dynamic/instance-identity/document response, not a dedicated
+	// placement/region path.
+	if got, err := client.Region(ctx); err != nil || got != fixtures.Region {
+		t.Fatalf("Region() = %q, %v; want %q, <nil>", got, err, fixtures.Region)
+	}
+
+	iamInfo, err := client.IAMInfo(ctx)
+	if err != nil {
+		t.Fatalf("IAMInfo(): %v", err)
+	}
+	if iamInfo.InstanceProfileArn != fixtures.IAMInfo.InstanceProfileArn {
+		t.Fatalf("IAMInfo().InstanceProfileArn = %q; want %q", iamInfo.InstanceProfileArn, fixtures.IAMInfo.InstanceProfileArn)
+	}
+}
+
+func TestServerRejectsRequestsWithoutToken(t *testing.T) {
+	server := startServer(t, mockimds.Fixtures{InstanceID: "i-0123456789abcdef0"})
+
+	resp, err := http.Get(server.Endpoint() + "/latest/meta-data/instance-id")
+	if err != nil {
+		t.Fatalf("GET instance-id: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status = %d; want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
@@ -2,25 +2,35 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"io"
 	"log"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+
+	"github.com/william-hou-ca/go-aws-sdk/01-get-metadata-from-ec2instances/internal/awsmeta"
+	cloudaws "github.com/william-hou-ca/go-aws-sdk/01-get-metadata-from-ec2instances/internal/cloud/aws"
 )
 
 func main() {
+	mockEndpoint := flag.String("mock-endpoint", "", "point the IMDS client at a mockimds server instead of real EC2 metadata (e.g. http://127.0.0.1:1338)")
+	flag.Parse()
+
 	ctx := context.TODO()
-	
+
 	// 1. Load default configuration and create IMDS client
 	cfg, err := config.LoadDefaultConfig(ctx)
 	if err != nil {
 		log.Fatalf("Unable to load AWS configuration: %v", err)
 	}
 
-	client := imds.NewFromConfig(cfg)
+	var clientOpts []func(*awsmeta.Options)
+	if *mockEndpoint != "" {
+		clientOpts = append(clientOpts, awsmeta.WithEndpoint(*mockEndpoint))
+	}
+	client := awsmeta.NewFromConfig(cfg, clientOpts...)
 
 	// 2. Get basic instance information
 	fmt.Println("=== EC2 Instance Metadata ===")
@@ -80,85 +90,92 @@ func main() {
 		fmt.Printf("IAM Role ARN: %s\n", iamInfo.InstanceProfileArn)
 		fmt.Printf("Last Updated: %s\n", iamInfo.LastUpdated.Format(time.RFC3339))
 	}
-}
 
-// Get instance ID
-func getInstanceID(client *imds.Client, ctx context.Context) (string, error) {
-	result, err := client.GetMetadata(ctx, &imds.GetMetadataInput{
-		Path: "instance-id",
-	})
+	// 5. Check network interface details via the typed accessor layer
+	fmt.Println("\n=== Network Interfaces ===")
+	typed := awsmeta.NewTypedIMDS(client)
+	mac, err := typed.GetMAC(ctx)
 	if err != nil {
-		return "", err
+		log.Printf("Warning: Unable to get MAC address: %v", err)
+	} else {
+		fmt.Printf("Primary MAC: %s\n", mac)
+
+		vpcID, err := typed.GetVPCID(ctx)
+		if err != nil {
+			log.Printf("Warning: Unable to get VPC ID: %v", err)
+		} else {
+			fmt.Printf("VPC ID: %s\n", vpcID)
+		}
+
+		subnetID, err := typed.GetSubnetID(ctx)
+		if err != nil {
+			log.Printf("Warning: Unable to get subnet ID: %v", err)
+		} else {
+			fmt.Printf("Subnet ID: %s\n", subnetID)
+		}
+
+		securityGroupIDs, err := typed.GetSecurityGroupIDs(ctx, mac)
+		if err != nil {
+			log.Printf("Warning: Unable to get security group IDs: %v", err)
+		} else {
+			fmt.Printf("Security Group IDs: %v\n", securityGroupIDs)
+		}
+	}
+
+	// 6. Combine IMDS with EC2 DescribeInstances for the full picture
+	fmt.Println("\n=== Deployment Metadata ===")
+	provider := cloudaws.New(cfg)
+	self, err := provider.Self(ctx)
+	if err != nil {
+		log.Printf("Warning: Unable to get self metadata: %v", err)
+	} else {
+		fmt.Printf("Name: %s\n", self.Name)
+		fmt.Printf("Role ARN: %s\n", self.Role)
+		fmt.Printf("Private IPs: %v\n", self.PrivateIPs)
+		fmt.Printf("Public IPs: %v\n", self.PublicIPs)
 	}
-	defer result.Content.Close()
-	
-	content, err := io.ReadAll(result.Content)
-	if err != nil {
-		return "", err
+
+	// 7. Start a background cache so repeated reads don't hammer IMDS
+	fmt.Println("\n=== Cached Metadata ===")
+	cache := awsmeta.NewMetadataCache(client, []awsmeta.Key{
+		awsmeta.KeyInstanceID,
+		awsmeta.KeyRegion,
+		awsmeta.KeyIAMRole,
+	}, awsmeta.WithKeyTTL(awsmeta.KeyIAMRole, 15*time.Minute))
+	cache.Start(ctx)
+	defer cache.Close()
+
+	if cachedID, ok := cache.Get(awsmeta.KeyInstanceID); ok {
+		fmt.Printf("Cached Instance ID: %v\n", cachedID)
 	}
-	
-	return string(content), nil
+}
+
+// Get instance ID
+func getInstanceID(client *awsmeta.Client, ctx context.Context) (string, error) {
+	return client.InstanceID(ctx)
 }
 
 // Get region
-func getRegion(client *imds.Client, ctx context.Context) (string, error) {
-	result, err := client.GetRegion(ctx, &imds.GetRegionInput{})
-	if err != nil {
-		return "", err
-	}
-	return result.Region, nil
+func getRegion(client *awsmeta.Client, ctx context.Context) (string, error) {
+	return client.Region(ctx)
 }
 
 // Get private IP address
-func getPrivateIP(client *imds.Client, ctx context.Context) (string, error) {
-	result, err := client.GetMetadata(ctx, &imds.GetMetadataInput{
-		Path: "local-ipv4",
-	})
-	if err != nil {
-		return "", err
-	}
-	defer result.Content.Close()
-	
-	content, err := io.ReadAll(result.Content)
-	if err != nil {
-		return "", err
-	}
-	
-	return string(content), nil
+func getPrivateIP(client *awsmeta.Client, ctx context.Context) (string, error) {
+	return client.PrivateIP(ctx)
 }
 
 // Get instance type
-func getInstanceType(client *imds.Client, ctx context.Context) (string, error) {
-	result, err := client.GetMetadata(ctx, &imds.GetMetadataInput{
-		Path: "instance-type",
-	})
-	if err != nil {
-		return "", err
-	}
-	defer result.Content.Close()
-	
-	content, err := io.ReadAll(result.Content)
-	if err != nil {
-		return "", err
-	}
-	
-	return string(content), nil
+func getInstanceType(client *awsmeta.Client, ctx context.Context) (string, error) {
+	return client.InstanceType(ctx)
 }
 
 // Get instance identity document
-func getInstanceIdentityDocument(client *imds.Client, ctx context.Context) (*imds.InstanceIdentityDocument, error) {
-	result, err := client.GetInstanceIdentityDocument(ctx, &imds.GetInstanceIdentityDocumentInput{})
-	if err != nil {
-		return nil, err
-	}
-	return &result.InstanceIdentityDocument, nil
+func getInstanceIdentityDocument(client *awsmeta.Client, ctx context.Context) (*imds.InstanceIdentityDocument, error) {
+	return client.InstanceIdentityDocument(ctx)
 }
 
 // Get IAM information
-func getIAMInfo(client *imds.Client, ctx context.Context) (*imds.IAMInfo, error) {
-	result, err := client.GetIAMInfo(ctx, &imds.GetIAMInfoInput{})
-	if err != nil {
-		return nil, err
-	}
-	return &result.IAMInfo, nil
+func getIAMInfo(client *awsmeta.Client, ctx context.Context) (*imds.IAMInfo, error) {
+	return client.IAMInfo(ctx)
 }